@@ -0,0 +1,53 @@
+package k8sprometheus
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MetricEnrichmentJoin configures an opt-in recording-rule group that joins an SLO's SLI
+// error/total rate recordings against a user-configured "topology" metric (e.g.
+// `kube_pod_info`, `sriov_kubepoddevice`), so downstream dashboards can slice SLO burn by pod,
+// namespace or node without repeating the join in every query. Users set this in the
+// `PrometheusServiceLevel` spec.
+type MetricEnrichmentJoin struct {
+	// SourceLabels are the labels the SLI recording already has that identify the join key
+	// (e.g. `pciAddress`, `pod`).
+	SourceLabels []string
+	// JoinMetric is the topology metric to join against (e.g. `kube_pod_info`).
+	JoinMetric string
+	// ExtraLabels are the labels propagated from the join metric (e.g. `pod`, `namespace`, `node`).
+	ExtraLabels []string
+}
+
+// valid reports whether the join carries enough configuration to build a parseable PromQL
+// expression. A misconfigured join (e.g. an empty `JoinMetric`) would otherwise produce something
+// like `(orig) * on (k) group_left(e) `, which fails PromQL validation in `BuildPrometheusRule`
+// and errors the whole CR, taking every other SLO sharing it down too.
+func (m MetricEnrichmentJoin) valid() bool {
+	return m.JoinMetric != "" && len(m.SourceLabels) > 0 && len(m.ExtraLabels) > 0
+}
+
+// enrichedRecordingRulesGroup builds the `sloth-slo-enriched-recordings-<id>` group for an SLO
+// that opted into metric enrichment, wrapping each SLI error recording in a `group_left` join
+// against the configured topology metric, in the same style as the sriov namespaced-metrics
+// rules. A misconfigured join is skipped rather than emitting a malformed expression.
+func enrichedRecordingRulesGroup(slo StorageSLO) (string, []RecordingRule) {
+	if slo.Enrichment == nil || len(slo.Rules.SLIErrorRecRules) == 0 || !slo.Enrichment.valid() {
+		return "", nil
+	}
+
+	on := strings.Join(slo.Enrichment.SourceLabels, ", ")
+	groupLeft := strings.Join(slo.Enrichment.ExtraLabels, ", ")
+
+	rules := make([]RecordingRule, 0, len(slo.Rules.SLIErrorRecRules))
+	for _, r := range slo.Rules.SLIErrorRecRules {
+		rules = append(rules, RecordingRule{
+			Record: fmt.Sprintf("%s_enriched", r.Record),
+			Expr:   fmt.Sprintf("(%s) * on (%s) group_left(%s) %s", r.Expr, on, groupLeft, slo.Enrichment.JoinMetric),
+			Labels: r.Labels,
+		})
+	}
+
+	return fmt.Sprintf("sloth-slo-enriched-recordings-%s", slo.SLO.ID), rules
+}
@@ -0,0 +1,33 @@
+package k8sprometheus
+
+// K8sMeta carries the Kubernetes identity needed to build and own the CRs `StoreSLOs` generates:
+// the generated CR's own name/namespace/labels/annotations, plus enough of the parent resource's
+// identity (kind, API version, name, UID) for `setOwnership` to point an `OwnerReference` back at
+// it.
+type K8sMeta struct {
+	// Kind and APIVersion identify the parent resource (e.g. a `PrometheusServiceLevel`) that owns
+	// the generated CR.
+	Kind       string
+	APIVersion string
+	// UID is the parent resource's UID, used verbatim in the `OwnerReference`.
+	UID string
+	// Name is the generated CR's own name (the base of the `<Name>-<shard>` names when sharding is
+	// enabled), and also the parent resource's name used for ownership.
+	Name string
+	// Namespace is the generated CR's own namespace.
+	Namespace string
+	Labels    map[string]string
+	// Annotations seeds the generated CR's annotations (e.g. operator-specific hints). Reconcile
+	// mode adds its own bookkeeping annotations (`lastAppliedGroupsAnnotation`,
+	// `ownerRefAnnotation`) alongside these.
+	Annotations map[string]string
+
+	// ParentNamespace is the parent resource's namespace, when the generated CR is stored in a
+	// different namespace than its parent (e.g. a cluster-scoped rollup CR, or a
+	// `PrometheusServiceLevel` that targets a shared monitoring namespace). Left empty, the parent
+	// is assumed to share the CR's own Namespace. Kubernetes forbids cross-namespace
+	// `OwnerReference`s, so `setOwnership` falls back to the `sloth.slok.dev/owner-ref` annotation
+	// whenever ParentNamespace differs from Namespace. Callers that store CRs outside the parent's
+	// namespace must set this field themselves.
+	ParentNamespace string
+}
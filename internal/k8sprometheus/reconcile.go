@@ -0,0 +1,169 @@
+package k8sprometheus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/slok/sloth/internal/log"
+)
+
+const (
+	// defaultFieldManager is the server-side apply field manager `PrometheusOperatorCRDRepo`
+	// uses when `ReconcileOptions.FieldManager` isn't set, so hand-edited fields owned by other
+	// managers (e.g. a human using `kubectl edit`) survive across runs.
+	defaultFieldManager = "sloth"
+
+	// lastAppliedGroupsAnnotation lists (comma-separated) the rule group names Sloth produced on
+	// its last apply, so the next reconciliation can detect and prune groups Sloth used to
+	// generate but doesn't produce anymore (e.g. because an SLO was removed from the spec).
+	lastAppliedGroupsAnnotation = "sloth.slok.dev/last-applied-groups"
+
+	// ownerRefAnnotation carries the owner reference when the generated CR lives in a different
+	// namespace than its parent, since Kubernetes forbids cross-namespace `OwnerReference`s.
+	ownerRefAnnotation = "sloth.slok.dev/owner-ref"
+)
+
+// ReconcileOptions configures the ownership-aware reconciliation mode of
+// `PrometheusOperatorCRDRepo`. When `Enabled` is false (the default), `StoreSLOs` keeps its
+// original single-upsert-plus-owner-reference behavior.
+type ReconcileOptions struct {
+	// Enabled switches `StoreSLOs` to server-side apply and stale rule group pruning.
+	Enabled bool
+	// FieldManager is the server-side apply field manager. Defaults to "sloth".
+	FieldManager string
+}
+
+func (r ReconcileOptions) fieldManager() string {
+	if r.FieldManager == "" {
+		return defaultFieldManager
+	}
+	return r.FieldManager
+}
+
+// reconcile stores `rule` and `amConfigs` using server-side apply, pruning rule groups Sloth
+// generated on a previous run but that the current spec no longer produces, and setting
+// ownership either as an `OwnerReference` or, when the CR lives in a different namespace than
+// its parent, as the `sloth.slok.dev/owner-ref` annotation.
+func (p PrometheusOperatorCRDRepo) reconcile(ctx context.Context, kmeta K8sMeta, rule *monitoringv1.PrometheusRule, amConfigs []*monitoringv1alpha1.AlertmanagerConfig) error {
+	setOwnership(&rule.ObjectMeta, kmeta)
+
+	err := p.applyRule(ctx, rule)
+	if err != nil {
+		return err
+	}
+
+	fieldManager := p.reconcileOpts.fieldManager()
+	for _, amConfig := range amConfigs {
+		setOwnership(&amConfig.ObjectMeta, kmeta)
+
+		err = p.ensurer.ApplyAlertmanagerConfig(ctx, amConfig, fieldManager)
+		if err != nil {
+			return fmt.Errorf("could not server-side apply Alertmanager config CR: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// applyRule server-side applies rule, pruning rule groups Sloth generated on a previous run but
+// that the current spec no longer produces. It doesn't touch ownership, so callers that want an
+// `OwnerReference` or the cross-namespace annotation must call `setOwnership` on rule first -
+// `storeGlobalRule` deliberately doesn't, since the shared global rule CR must not be owned by
+// any single `PrometheusServiceLevel`.
+func (p PrometheusOperatorCRDRepo) applyRule(ctx context.Context, rule *monitoringv1.PrometheusRule) error {
+	fieldManager := p.reconcileOpts.fieldManager()
+
+	groupNames := ruleGroupNames(rule)
+	if rule.Annotations == nil {
+		rule.Annotations = map[string]string{}
+	}
+	rule.Annotations[lastAppliedGroupsAnnotation] = strings.Join(groupNames, ",")
+
+	previous, err := p.ensurer.GetPrometheusRule(ctx, rule.Namespace, rule.Name)
+	if err != nil {
+		return fmt.Errorf("could not get previous Prometheus rule CR: %w", err)
+	}
+	stale := staleGroupNames(previous, groupNames)
+
+	err = p.ensurer.ApplyPrometheusRule(ctx, rule, fieldManager)
+	if err != nil {
+		return fmt.Errorf("could not server-side apply Prometheus operator rule CR: %w", err)
+	}
+
+	// `rule.Spec.Groups` only ever holds the groups this run produced, and prometheus-operator's
+	// `RuleGroups` is an atomic (non-associative) list field, so the apply above is owned
+	// wholesale by `fieldManager` and already dropped any group not in `groupNames`. This just
+	// reports what got pruned, after the fact.
+	if len(stale) > 0 {
+		p.logger.WithValues(log.Kv{"cr_namespace": rule.Namespace, "cr_name": rule.Name}).
+			Infof("pruned %d stale rule groups no longer produced: %s", len(stale), strings.Join(stale, ","))
+	}
+
+	return nil
+}
+
+// setOwnership sets an `OwnerReference` on `meta` when the CR shares its parent's namespace, or
+// the `sloth.slok.dev/owner-ref` annotation when it doesn't, since Kubernetes forbids
+// cross-namespace `OwnerReference`s.
+func setOwnership(meta *metav1.ObjectMeta, kmeta K8sMeta) {
+	parentNamespace := kmeta.ParentNamespace
+	if parentNamespace == "" {
+		parentNamespace = kmeta.Namespace
+	}
+
+	if parentNamespace == meta.Namespace {
+		meta.OwnerReferences = append(meta.OwnerReferences, metav1.OwnerReference{
+			Kind:       kmeta.Kind,
+			APIVersion: kmeta.APIVersion,
+			Name:       kmeta.Name,
+			UID:        types.UID(kmeta.UID),
+		})
+		return
+	}
+
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[ownerRefAnnotation] = fmt.Sprintf("%s/%s/%s/%s", kmeta.APIVersion, kmeta.Kind, parentNamespace, kmeta.Name)
+}
+
+func ruleGroupNames(rule *monitoringv1.PrometheusRule) []string {
+	names := make([]string, 0, len(rule.Spec.Groups))
+	for _, g := range rule.Spec.Groups {
+		names = append(names, g.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func staleGroupNames(previous *monitoringv1.PrometheusRule, currentGroupNames []string) []string {
+	if previous == nil {
+		return nil
+	}
+
+	current := make(map[string]struct{}, len(currentGroupNames))
+	for _, name := range currentGroupNames {
+		current[name] = struct{}{}
+	}
+
+	prevGroups := strings.Split(previous.Annotations[lastAppliedGroupsAnnotation], ",")
+	var stale []string
+	for _, name := range prevGroups {
+		if name == "" {
+			continue
+		}
+		if _, ok := current[name]; !ok {
+			stale = append(stale, name)
+		}
+	}
+	sort.Strings(stale)
+
+	return stale
+}
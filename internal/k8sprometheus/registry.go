@@ -0,0 +1,225 @@
+package k8sprometheus
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/prometheus/promql/parser"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// RecordingRule is a typed, registry-friendly recording rule. It deliberately has no alert-only
+// fields (`For`, `Annotations`), so a contributor can't set them on a recording by mistake.
+type RecordingRule struct {
+	Record string
+	Expr   string
+	Labels map[string]string
+}
+
+// Alert is a typed, registry-friendly alerting rule.
+type Alert struct {
+	Alert       string
+	Expr        string
+	For         string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// RuleGroup is a named collection of recording rules and alerts that maps 1:1 onto a
+// `monitoringv1.RuleGroup`.
+type RuleGroup struct {
+	Name           string
+	RecordingRules []RecordingRule
+	Alerts         []Alert
+}
+
+// Registry collects typed recording rules and alerts under named groups, analogous to
+// operator-observability's `operatorrules.Registry`. Packages (including external ones that
+// import `k8sprometheus`) register their rules on `DefaultRegistry` at `init` time so extra
+// aggregation layers (cost, capacity, burn-rate projections, ...) get stored next to the SLO
+// rules without forking `BuildPrometheusRule`. Those groups go into their own dedicated CR; they
+// are not merged into the CRs holding the SLO rules.
+type Registry struct {
+	mu             sync.Mutex
+	recordingRules map[string][]RecordingRule
+	alerts         map[string][]Alert
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		recordingRules: map[string][]RecordingRule{},
+		alerts:         map[string][]Alert{},
+	}
+}
+
+// RegisterRecordingRules adds recording rules to a named group.
+func (r *Registry) RegisterRecordingRules(group string, rules ...RecordingRule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recordingRules[group] = append(r.recordingRules[group], rules...)
+}
+
+// RegisterAlerts adds alerts to a named group.
+func (r *Registry) RegisterAlerts(group string, alerts ...Alert) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts[group] = append(r.alerts[group], alerts...)
+}
+
+// Groups returns the groups held by the registry, sorted by name so CR output is stable.
+func (r *Registry) Groups() []RuleGroup {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := map[string]struct{}{}
+	for name := range r.recordingRules {
+		names[name] = struct{}{}
+	}
+	for name := range r.alerts {
+		names[name] = struct{}{}
+	}
+
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	groups := make([]RuleGroup, 0, len(sortedNames))
+	for _, name := range sortedNames {
+		groups = append(groups, RuleGroup{
+			Name:           name,
+			RecordingRules: r.recordingRules[name],
+			Alerts:         r.alerts[name],
+		})
+	}
+
+	return groups
+}
+
+// DefaultRegistry is the package-level registry that plugins and external Go code can register
+// extra recording rules and alerts into. `StoreSLOs` builds its groups into the dedicated
+// `globalRulesCRName` CR of the namespace, stored next to the SLO-derived CRs; they are not
+// merged into those CRs, and `BuildPrometheusRule` itself does not read this registry.
+var DefaultRegistry = NewRegistry()
+
+// RegisterRecordingRules registers recording rules on the `DefaultRegistry`.
+func RegisterRecordingRules(group string, rules ...RecordingRule) {
+	DefaultRegistry.RegisterRecordingRules(group, rules...)
+}
+
+// RegisterAlerts registers alerts on the `DefaultRegistry`.
+func RegisterAlerts(group string, alerts ...Alert) {
+	DefaultRegistry.RegisterAlerts(group, alerts...)
+}
+
+// BuildPrometheusRule validates and assembles `groups` into a `PrometheusRule` CR. Every rule is
+// required to have a non-empty expression that parses as valid PromQL, and a record name
+// repeated within a group is rejected with an error so two contributors can't silently clobber
+// each other's series.
+func BuildPrometheusRule(kmeta K8sMeta, groups ...RuleGroup) (*monitoringv1.PrometheusRule, error) {
+	labels := map[string]string{
+		"app.kubernetes.io/component":  "SLO",
+		"app.kubernetes.io/managed-by": "sloth",
+	}
+	for k, v := range kmeta.Labels {
+		labels[k] = v
+	}
+
+	// Copy the annotations, so bookkeeping annotations added to one CR don't leak into kmeta
+	// and from there into every other CR built from it.
+	var annotations map[string]string
+	if kmeta.Annotations != nil {
+		annotations = make(map[string]string, len(kmeta.Annotations))
+		for k, v := range kmeta.Annotations {
+			annotations[k] = v
+		}
+	}
+
+	rule := &monitoringv1.PrometheusRule{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "monitoring.coreos.com/v1",
+			Kind:       "PrometheusRule",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        kmeta.Name,
+			Namespace:   kmeta.Namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+	}
+
+	for _, group := range groups {
+		kubeGroup, err := buildKubeRuleGroup(group)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule group %q: %w", group.Name, err)
+		}
+		if kubeGroup == nil {
+			continue
+		}
+		rule.Spec.Groups = append(rule.Spec.Groups, *kubeGroup)
+	}
+
+	return rule, nil
+}
+
+func buildKubeRuleGroup(group RuleGroup) (*monitoringv1.RuleGroup, error) {
+	if len(group.RecordingRules) == 0 && len(group.Alerts) == 0 {
+		return nil, nil
+	}
+
+	recordNames := map[string]struct{}{}
+	rules := make([]monitoringv1.Rule, 0, len(group.RecordingRules)+len(group.Alerts))
+
+	for _, rr := range group.RecordingRules {
+		if rr.Record == "" {
+			return nil, fmt.Errorf("recording rule requires a record name")
+		}
+		if _, ok := recordNames[rr.Record]; ok {
+			return nil, fmt.Errorf("duplicated record name %q", rr.Record)
+		}
+		recordNames[rr.Record] = struct{}{}
+
+		if err := validatePromQLExpr(rr.Expr); err != nil {
+			return nil, fmt.Errorf("recording rule %q: %w", rr.Record, err)
+		}
+
+		rules = append(rules, monitoringv1.Rule{
+			Record: rr.Record,
+			Expr:   intstr.FromString(rr.Expr),
+			Labels: rr.Labels,
+		})
+	}
+
+	for _, al := range group.Alerts {
+		if al.Alert == "" {
+			return nil, fmt.Errorf("alert requires an alert name")
+		}
+		if err := validatePromQLExpr(al.Expr); err != nil {
+			return nil, fmt.Errorf("alert %q: %w", al.Alert, err)
+		}
+
+		rules = append(rules, monitoringv1.Rule{
+			Alert:       al.Alert,
+			Expr:        intstr.FromString(al.Expr),
+			For:         al.For,
+			Labels:      al.Labels,
+			Annotations: al.Annotations,
+		})
+	}
+
+	return &monitoringv1.RuleGroup{Name: group.Name, Rules: rules}, nil
+}
+
+func validatePromQLExpr(expr string) error {
+	if expr == "" {
+		return fmt.Errorf("expr is required")
+	}
+	if _, err := parser.ParseExpr(expr); err != nil {
+		return fmt.Errorf("invalid PromQL expression: %w", err)
+	}
+	return nil
+}
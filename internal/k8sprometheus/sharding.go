@@ -0,0 +1,121 @@
+package k8sprometheus
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"github.com/slok/sloth/internal/prometheus"
+)
+
+// shardOfLabel marks which base CR name a sharded `PrometheusRule` belongs to, so
+// `PrometheusOperatorCRDRepo` can list and garbage-collect shards that became empty.
+// `AlertmanagerConfig` CRs don't carry it.
+const shardOfLabel = "sloth.slok.dev/shard-of"
+
+// ShardingStrategy splits the SLOs of a single `StoreSLOs` call across multiple
+// `PrometheusRule`/`AlertmanagerConfig` CRs, named deterministically `<kmeta.Name>-<shard>`,
+// instead of packing every SLO into one CR. This avoids hitting the etcd 1.5 MB object limit and
+// the Prometheus-Operator per-rule-file evaluation cost once a namespace has hundreds of SLOs.
+//
+// The zero value disables sharding (everything goes into a single `<kmeta.Name>` CR, the
+// original behavior).
+type ShardingStrategy struct {
+	// ShardCount fixes the number of shards each bucket is split into. It's the authority on shard
+	// count: unlike `MaxGroupsPerCR`/`MaxBytesPerCR`, it never changes as SLOs are added or
+	// removed, so `shardIndex`'s `hash(slo.ID) % ShardCount` keeps mapping a given SLO to the same
+	// shard across runs. Deriving the shard count from the bucket's current footprint instead
+	// would change it (and therefore reshuffle nearly every SLO into a different CR) the moment
+	// that footprint crossed a threshold - exactly the rule-restart storm sharding exists to avoid.
+	ShardCount int
+	// MaxGroupsPerCR is an approximate guide for picking `ShardCount`: how many rule groups
+	// (SLI/meta recordings, alerts, enrichment) a shard is expected to hold on average. It is not
+	// enforced as a hard per-CR bound - with a fixed `ShardCount`, hashing can still land more SLOs
+	// (and therefore more groups) in one shard than in another. Zero means unused.
+	MaxGroupsPerCR int
+	// MaxBytesPerCR is an approximate guide for picking `ShardCount`: the serialized size (sum of
+	// rule names and expressions) a shard is expected to hold on average. Like `MaxGroupsPerCR`,
+	// it's not a hard per-CR bound under hash-based assignment. Zero means unused.
+	MaxBytesPerCR int
+	// KeyFunc, when set, buckets SLOs by a user-supplied key (e.g. team, tier) before sharding
+	// them, so SLOs sharing a key always land in the same family of shards.
+	KeyFunc func(slo prometheus.SLO) string
+}
+
+func (s ShardingStrategy) enabled() bool {
+	return s.ShardCount > 0 || s.KeyFunc != nil
+}
+
+// shard is one `<kmeta.Name>-<shard>` CR worth of SLOs.
+type shard struct {
+	name string
+	slos []StorageSLO
+}
+
+// shardSLOs splits slos deterministically into shards. SLOs are first bucketed by `KeyFunc` (a
+// single "" bucket when unset), then, within a bucket, assigned to one of `<baseName>-<key>-<index>`
+// shards (`<baseName>-<index>` for the "" bucket, index zero-padded to two digits) by hashing the SLO ID modulo the fixed `ShardCount`. Because `ShardCount` doesn't depend
+// on the bucket's membership, a given SLO lands in the same shard across runs regardless of which
+// other SLOs come and go, instead of a first-fit bin-pack (or a shard count derived from the
+// current footprint) where adding or removing one SLO shifts others across shard boundaries -
+// avoiding rule-restart storms. `MaxGroupsPerCR`/`MaxBytesPerCR` only inform what `ShardCount`
+// should be set to; they aren't enforced per shard, so a shard can still end up bigger than them.
+func (s ShardingStrategy) shardSLOs(baseName string, slos []StorageSLO) []shard {
+	if !s.enabled() {
+		return []shard{{name: baseName, slos: slos}}
+	}
+
+	buckets := map[string][]StorageSLO{}
+	var bucketKeys []string
+	for _, slo := range slos {
+		key := ""
+		if s.KeyFunc != nil {
+			key = s.KeyFunc(slo.SLO)
+		}
+		if _, ok := buckets[key]; !ok {
+			bucketKeys = append(bucketKeys, key)
+		}
+		buckets[key] = append(buckets[key], slo)
+	}
+	sort.Strings(bucketKeys)
+
+	numShards := s.ShardCount
+	if numShards <= 0 {
+		numShards = 1
+	}
+
+	var shards []shard
+	for _, key := range bucketKeys {
+		bucketSLOs := buckets[key]
+		sort.Slice(bucketSLOs, func(i, j int) bool { return bucketSLOs[i].SLO.ID < bucketSLOs[j].SLO.ID })
+
+		shardSLOGroups := make([][]StorageSLO, numShards)
+		for _, slo := range bucketSLOs {
+			idx := shardIndex(slo.SLO.ID, numShards)
+			shardSLOGroups[idx] = append(shardSLOGroups[idx], slo)
+		}
+
+		for idx, slos := range shardSLOGroups {
+			if len(slos) == 0 {
+				continue
+			}
+			shards = append(shards, shard{name: shardName(baseName, key, idx), slos: slos})
+		}
+	}
+
+	return shards
+}
+
+// shardIndex deterministically maps an SLO ID to one of numShards shards via FNV-1a.
+func shardIndex(sloID string, numShards int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(sloID))
+	return int(h.Sum32() % uint32(numShards))
+}
+
+func shardName(baseName, key string, idx int) string {
+	if key == "" {
+		return fmt.Sprintf("%s-%02d", baseName, idx)
+	}
+	return fmt.Sprintf("%s-%s-%02d", baseName, key, idx)
+}
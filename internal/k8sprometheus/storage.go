@@ -3,16 +3,19 @@ package k8sprometheus
 import (
 	"bytes"
 	"context"
+	stdjson "encoding/json"
 	"fmt"
 	"io"
 
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
 	"github.com/prometheus/prometheus/model/rulefmt"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer/json"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/slok/sloth/internal/info"
 	"github.com/slok/sloth/internal/log"
@@ -25,98 +28,205 @@ var (
 	ErrNoSLORules = fmt.Errorf("0 SLO Prometheus rules generated")
 )
 
-func NewIOWriterPrometheusOperatorYAMLRepo(writer io.Writer, logger log.Logger) IOWriterPrometheusOperatorYAMLRepo {
+// globalRulesCRName is the fixed name of the `PrometheusRule` CR that holds every group
+// registered on `DefaultRegistry`. It's stored once per namespace under this name instead of
+// being merged into each SLO/shard CR, so plugin/global recording rules aren't duplicated across
+// every `PrometheusServiceLevel`'s (or shard's) CR in the namespace.
+const globalRulesCRName = "sloth-global-rules"
+
+func NewIOWriterPrometheusOperatorYAMLRepo(writer io.Writer, sharding ShardingStrategy, logger log.Logger) IOWriterPrometheusOperatorYAMLRepo {
 	return IOWriterPrometheusOperatorYAMLRepo{
-		writer:  writer,
-		encoder: json.NewYAMLSerializer(json.DefaultMetaFactory, nil, nil),
-		logger:  logger.WithValues(log.Kv{"svc": "storage.IOWriter", "format": "k8s-prometheus-operator"}),
+		writer:   writer,
+		sharding: sharding,
+		encoder:  json.NewYAMLSerializer(json.DefaultMetaFactory, nil, nil),
+		logger:   logger.WithGroup("k8sprometheus").WithValues(log.Kv{"svc": "storage.IOWriter", "format": "k8s-prometheus-operator"}),
 	}
 }
 
 // IOWriterPrometheusOperatorYAMLRepo knows to store all the SLO rules (recordings and alerts)
 // grouped in an IOWriter in Kubernetes prometheus operator YAML format.
 type IOWriterPrometheusOperatorYAMLRepo struct {
-	writer  io.Writer
-	encoder runtime.Encoder
-	logger  log.Logger
+	writer   io.Writer
+	sharding ShardingStrategy
+	encoder  runtime.Encoder
+	logger   log.Logger
 }
 
 type StorageSLO struct {
-	SLO   prometheus.SLO
-	Rules prometheus.SLORules
+	SLO       prometheus.SLO
+	Rules     prometheus.SLORules
+	Receivers []SLOReceiver
+	// Enrichment opts this SLO into a `group_left` namespaced-metric enrichment recordings group.
+	Enrichment *MetricEnrichmentJoin
 }
 
-func (i IOWriterPrometheusOperatorYAMLRepo) StoreSLOs(ctx context.Context, kmeta K8sMeta, slos []StorageSLO) error {
-	rule, err := mapModelToPrometheusOperator(ctx, kmeta, slos)
-	if err != nil {
-		return fmt.Errorf("could not map model to Prometheus operator CR: %w", err)
-	}
+// SLOReceiver describes where the alerts generated for an SLO should be routed to. When at
+// least one is set on a StorageSLO, an `AlertmanagerConfig` CR is generated alongside the
+// `PrometheusRule` one, routing on the same labels Sloth already stamps on its alerts
+// (`severity`, `sloth_service`, `sloth_slo`).
+type SLOReceiver struct {
+	Name string
+	// Severity, when set, scopes this receiver's route to alerts carrying that `severity` label
+	// (e.g. "page", "ticket"), so page and ticket alerts for the same SLO can route to different
+	// receivers. Left empty, the route matches on `sloth_service`/`sloth_slo` alone and catches
+	// alerts of every severity.
+	Severity       string
+	GroupBy        []string
+	GroupWait      string
+	GroupInterval  string
+	RepeatInterval string
+	Slack          *SlackReceiver
+	PagerDuty      *PagerDutyReceiver
+	Webhook        *WebhookReceiver
+}
+
+type SlackReceiver struct {
+	APIURLSecretRef corev1.SecretKeySelector
+	Channel         string
+}
+
+type PagerDutyReceiver struct {
+	RoutingKeySecretRef corev1.SecretKeySelector
+}
+
+type WebhookReceiver struct {
+	URL string
+}
+
+func (i IOWriterPrometheusOperatorYAMLRepo) StoreSLOs(ctx context.Context, kmeta K8sMeta, slos []StorageSLO) ([]string, error) {
+	shards := i.sharding.shardSLOs(kmeta.Name, slos)
 
 	var b bytes.Buffer
-	err = i.encoder.Encode(rule, &b)
+	crNames := make([]string, 0, len(shards))
+	for _, shard := range shards {
+		shardKmeta := kmeta
+		shardKmeta.Name = shard.name
+
+		rule, amConfigs, err := mapModelToPrometheusOperator(ctx, shardKmeta, shard.slos)
+		if err != nil {
+			return nil, fmt.Errorf("could not map model to Prometheus operator CR: %w", err)
+		}
+
+		if b.Len() > 0 {
+			b.WriteString("---\n")
+		}
+		err = i.encoder.Encode(rule, &b)
+		if err != nil {
+			return nil, fmt.Errorf("could encode prometheus operator object: %w", err)
+		}
+		for _, amConfig := range amConfigs {
+			b.WriteString("---\n")
+			err = i.encoder.Encode(amConfig, &b)
+			if err != nil {
+				return nil, fmt.Errorf("could not encode Alertmanager config object: %w", err)
+			}
+		}
+
+		for _, slo := range shard.slos {
+			i.logger.WithValues(log.Kv{
+				"slo_id":       slo.SLO.ID,
+				"cr_namespace": kmeta.Namespace,
+				"cr_name":      shard.name,
+				"group_count":  len(rule.Spec.Groups),
+				"rule_count":   countRules(rule),
+			}).Debugf("stored SLO Prometheus rules")
+		}
+
+		crNames = append(crNames, shard.name)
+	}
+
+	globalRule, err := mapDefaultRegistryToPrometheusRule(kmeta)
 	if err != nil {
-		return fmt.Errorf("could encode prometheus operator object: %w", err)
+		return nil, fmt.Errorf("could not map global registry to Prometheus operator CR: %w", err)
+	}
+	if globalRule != nil {
+		if b.Len() > 0 {
+			b.WriteString("---\n")
+		}
+		err = i.encoder.Encode(globalRule, &b)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode global Prometheus rule object: %w", err)
+		}
+		crNames = append(crNames, globalRule.Name)
 	}
 
 	rulesYaml := writeTopDisclaimer(b.Bytes())
 	_, err = i.writer.Write(rulesYaml)
 	if err != nil {
-		return fmt.Errorf("could not write top disclaimer: %w", err)
+		return nil, fmt.Errorf("could not write top disclaimer: %w", err)
 	}
 
-	return nil
+	i.logger.WithValues(log.Kv{"cr_namespace": kmeta.Namespace, "cr_count": len(crNames)}).Infof("wrote Prometheus operator YAML")
+
+	return crNames, nil
 }
 
-func mapModelToPrometheusOperator(ctx context.Context, kmeta K8sMeta, slos []StorageSLO) (*monitoringv1.PrometheusRule, error) {
-	// Add extra labels.
-	labels := map[string]string{
-		"app.kubernetes.io/component":  "SLO",
-		"app.kubernetes.io/managed-by": "sloth",
-	}
-	for k, v := range kmeta.Labels {
-		labels[k] = v
+func countRules(rule *monitoringv1.PrometheusRule) int {
+	n := 0
+	for _, g := range rule.Spec.Groups {
+		n += len(g.Rules)
 	}
+	return n
+}
 
-	rule := &monitoringv1.PrometheusRule{
-		TypeMeta: metav1.TypeMeta{
-			APIVersion: "monitoring.coreos.com/v1",
-			Kind:       "PrometheusRule",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:        kmeta.Name,
-			Namespace:   kmeta.Namespace,
-			Labels:      labels,
-			Annotations: kmeta.Annotations,
-		},
+// mapModelToPrometheusOperator maps the SLO model to the `PrometheusRule` CR holding all the
+// recording and alert rules, and, for every SLO declaring receiver routing metadata, an
+// `AlertmanagerConfig` CR routing the alerts it just generated to those receivers.
+func mapModelToPrometheusOperator(ctx context.Context, kmeta K8sMeta, slos []StorageSLO) (*monitoringv1.PrometheusRule, []*monitoringv1alpha1.AlertmanagerConfig, error) {
+	rule, err := mapModelToPrometheusRule(ctx, kmeta, slos)
+	if err != nil {
+		return nil, nil, err
 	}
 
+	amConfigs := mapModelToAlertmanagerConfigs(kmeta, slos)
+
+	return rule, amConfigs, nil
+}
+
+// mapModelToPrometheusRule registers every SLO's recording and alert rules into a fresh
+// `Registry` and builds the resulting `PrometheusRule` CR from it, instead of walking
+// `promRulesToKubeRules` by hand. Whatever plugins and external Go code registered on
+// `DefaultRegistry` (e.g. cost, capacity or burn-rate projections) is built separately by
+// `mapDefaultRegistryToPrometheusRule`, so it ends up in its own CR rather than duplicated into
+// every SLO/shard CR.
+func mapModelToPrometheusRule(ctx context.Context, kmeta K8sMeta, slos []StorageSLO) (*monitoringv1.PrometheusRule, error) {
 	if len(slos) == 0 {
 		return nil, fmt.Errorf("slo rules required")
 	}
 
+	registry := NewRegistry()
 	for _, slo := range slos {
 		if len(slo.Rules.SLIErrorRecRules) > 0 {
-			rule.Spec.Groups = append(rule.Spec.Groups, monitoringv1.RuleGroup{
-				Name:  fmt.Sprintf("sloth-slo-sli-recordings-%s", slo.SLO.ID),
-				Rules: promRulesToKubeRules(slo.Rules.SLIErrorRecRules),
-			})
+			registry.RegisterRecordingRules(
+				fmt.Sprintf("sloth-slo-sli-recordings-%s", slo.SLO.ID),
+				recordingRulesFromRulefmt(slo.Rules.SLIErrorRecRules)...,
+			)
 		}
 
 		if len(slo.Rules.MetadataRecRules) > 0 {
-			rule.Spec.Groups = append(rule.Spec.Groups, monitoringv1.RuleGroup{
-				Name:  fmt.Sprintf("sloth-slo-meta-recordings-%s", slo.SLO.ID),
-				Rules: promRulesToKubeRules(slo.Rules.MetadataRecRules),
-			})
+			registry.RegisterRecordingRules(
+				fmt.Sprintf("sloth-slo-meta-recordings-%s", slo.SLO.ID),
+				recordingRulesFromRulefmt(slo.Rules.MetadataRecRules)...,
+			)
 		}
 
 		if len(slo.Rules.AlertRules) > 0 {
-			rule.Spec.Groups = append(rule.Spec.Groups, monitoringv1.RuleGroup{
-				Name:  fmt.Sprintf("sloth-slo-alerts-%s", slo.SLO.ID),
-				Rules: promRulesToKubeRules(slo.Rules.AlertRules),
-			})
+			registry.RegisterAlerts(
+				fmt.Sprintf("sloth-slo-alerts-%s", slo.SLO.ID),
+				alertsFromRulefmt(slo.Rules.AlertRules)...,
+			)
+		}
+
+		if group, rules := enrichedRecordingRulesGroup(slo); group != "" {
+			registry.RegisterRecordingRules(group, rules...)
 		}
 	}
 
+	rule, err := BuildPrometheusRule(kmeta, registry.Groups()...)
+	if err != nil {
+		return nil, fmt.Errorf("could not build Prometheus rule CR: %w", err)
+	}
+
 	// If we don't have anything to store, error so we can increase the reliability
 	// because maybe this was due to an unintended error (typos, misconfig, too many disable...).
 	if len(rule.Spec.Groups) == 0 {
@@ -126,18 +236,49 @@ func mapModelToPrometheusOperator(ctx context.Context, kmeta K8sMeta, slos []Sto
 	return rule, nil
 }
 
-func promRulesToKubeRules(rules []rulefmt.Rule) []monitoringv1.Rule {
-	res := make([]monitoringv1.Rule, 0, len(rules))
+// mapDefaultRegistryToPrometheusRule builds the dedicated `globalRulesCRName` `PrometheusRule` CR
+// for whatever plugins and external Go code registered on `DefaultRegistry`. It returns a nil
+// rule when the registry is empty, so callers can skip storing it.
+func mapDefaultRegistryToPrometheusRule(kmeta K8sMeta) (*monitoringv1.PrometheusRule, error) {
+	groups := DefaultRegistry.Groups()
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	globalKmeta := kmeta
+	globalKmeta.Name = globalRulesCRName
+
+	rule, err := BuildPrometheusRule(globalKmeta, groups...)
+	if err != nil {
+		return nil, fmt.Errorf("could not build global Prometheus rule CR: %w", err)
+	}
+
+	return rule, nil
+}
+
+func recordingRulesFromRulefmt(rules []rulefmt.Rule) []RecordingRule {
+	res := make([]RecordingRule, 0, len(rules))
+	for _, r := range rules {
+		res = append(res, RecordingRule{
+			Record: r.Record,
+			Expr:   r.Expr,
+			Labels: r.Labels,
+		})
+	}
+	return res
+}
+
+func alertsFromRulefmt(rules []rulefmt.Rule) []Alert {
+	res := make([]Alert, 0, len(rules))
 	for _, r := range rules {
 		forS := ""
 		if r.For != 0 {
 			forS = r.For.String()
 		}
 
-		res = append(res, monitoringv1.Rule{
-			Record:      r.Record,
+		res = append(res, Alert{
 			Alert:       r.Alert,
-			Expr:        intstr.FromString(r.Expr),
+			Expr:        r.Expr,
 			For:         forS,
 			Labels:      r.Labels,
 			Annotations: r.Annotations,
@@ -146,6 +287,104 @@ func promRulesToKubeRules(rules []rulefmt.Rule) []monitoringv1.Rule {
 	return res
 }
 
+// mapModelToAlertmanagerConfigs builds one `AlertmanagerConfig` CR per SLO that declared
+// receiver routing metadata, routing on the same labels Sloth stamps on its page/ticket
+// alerts (`severity`, `sloth_service`, `sloth_slo`) so the CR is self-contained.
+func mapModelToAlertmanagerConfigs(kmeta K8sMeta, slos []StorageSLO) []*monitoringv1alpha1.AlertmanagerConfig {
+	var amConfigs []*monitoringv1alpha1.AlertmanagerConfig
+
+	for _, slo := range slos {
+		if len(slo.Receivers) == 0 {
+			continue
+		}
+
+		amConfig := &monitoringv1alpha1.AlertmanagerConfig{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "monitoring.coreos.com/v1alpha1",
+				Kind:       "AlertmanagerConfig",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%s", kmeta.Name, slo.SLO.ID),
+				Namespace: kmeta.Namespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/component":  "SLO",
+					"app.kubernetes.io/managed-by": "sloth",
+				},
+			},
+		}
+
+		for _, rcv := range slo.Receivers {
+			amConfig.Spec.Receivers = append(amConfig.Spec.Receivers, mapSLOReceiverToKubeReceiver(rcv))
+			amConfig.Spec.Route = appendReceiverRoute(amConfig.Spec.Route, slo.SLO, rcv)
+		}
+
+		// The root route references the "null" receiver (see appendReceiverRoute), which must
+		// exist in Spec.Receivers or prometheus-operator rejects the whole CR.
+		amConfig.Spec.Receivers = append(amConfig.Spec.Receivers, monitoringv1alpha1.Receiver{Name: "null"})
+
+		amConfigs = append(amConfigs, amConfig)
+	}
+
+	return amConfigs
+}
+
+func appendReceiverRoute(route *monitoringv1alpha1.Route, slo prometheus.SLO, rcv SLOReceiver) *monitoringv1alpha1.Route {
+	if route == nil {
+		route = &monitoringv1alpha1.Route{Receiver: "null"}
+	}
+
+	matchers := []monitoringv1alpha1.Matcher{
+		{Name: "sloth_service", Value: slo.Service, MatchType: monitoringv1alpha1.MatchEqual},
+		{Name: "sloth_slo", Value: slo.ID, MatchType: monitoringv1alpha1.MatchEqual},
+	}
+	if rcv.Severity != "" {
+		matchers = append(matchers, monitoringv1alpha1.Matcher{Name: "severity", Value: rcv.Severity, MatchType: monitoringv1alpha1.MatchEqual})
+	}
+
+	route.Routes = append(route.Routes, apiextensionsv1.JSON{Raw: mustMarshalRoute(monitoringv1alpha1.Route{
+		Receiver:       rcv.Name,
+		GroupBy:        rcv.GroupBy,
+		GroupWait:      rcv.GroupWait,
+		GroupInterval:  rcv.GroupInterval,
+		RepeatInterval: rcv.RepeatInterval,
+		Matchers:       matchers,
+		Continue:       true,
+	})})
+
+	return route
+}
+
+func mustMarshalRoute(r monitoringv1alpha1.Route) []byte {
+	bs, err := stdjson.Marshal(r)
+	if err != nil {
+		// A `Route` with only plain fields and no nested `Routes` always marshals cleanly.
+		panic(fmt.Sprintf("could not marshal Alertmanager route: %s", err))
+	}
+	return bs
+}
+
+func mapSLOReceiverToKubeReceiver(rcv SLOReceiver) monitoringv1alpha1.Receiver {
+	receiver := monitoringv1alpha1.Receiver{Name: rcv.Name}
+
+	switch {
+	case rcv.Slack != nil:
+		receiver.SlackConfigs = []monitoringv1alpha1.SlackConfig{{
+			APIURL:  &rcv.Slack.APIURLSecretRef,
+			Channel: rcv.Slack.Channel,
+		}}
+	case rcv.PagerDuty != nil:
+		receiver.PagerDutyConfigs = []monitoringv1alpha1.PagerDutyConfig{{
+			RoutingKey: &rcv.PagerDuty.RoutingKeySecretRef,
+		}}
+	case rcv.Webhook != nil:
+		receiver.WebhookConfigs = []monitoringv1alpha1.WebhookConfig{{
+			URL: &rcv.Webhook.URL,
+		}}
+	}
+
+	return receiver
+}
+
 func writeTopDisclaimer(bs []byte) []byte {
 	return append([]byte(disclaimer), bs...)
 }
@@ -157,40 +396,140 @@ var disclaimer = fmt.Sprintf(`
 
 `, info.Version)
 
-func NewPrometheusOperatorCRDRepo(ensurer PrometheusRulesEnsurer, logger log.Logger) PrometheusOperatorCRDRepo {
+func NewPrometheusOperatorCRDRepo(ensurer PrometheusRulesEnsurer, reconcileOpts ReconcileOptions, sharding ShardingStrategy, logger log.Logger) PrometheusOperatorCRDRepo {
 	return PrometheusOperatorCRDRepo{
-		ensurer: ensurer,
-		logger:  logger.WithValues(log.Kv{"svc": "storage.PrometheusOperatorCRDAPIServer", "format": "k8s-prometheus-operator"}),
+		ensurer:       ensurer,
+		reconcileOpts: reconcileOpts,
+		sharding:      sharding,
+		logger:        logger.WithGroup("k8sprometheus").WithValues(log.Kv{"svc": "storage.PrometheusOperatorCRDAPIServer", "format": "k8s-prometheus-operator"}),
 	}
 }
 
 // PrometheusOperatorCRDRepo knows to store all the SLO rules (recordings and alerts)
 // grouped as a Kubernetes prometheus operator CR using Kubernetes API server.
 type PrometheusOperatorCRDRepo struct {
-	logger  log.Logger
-	ensurer PrometheusRulesEnsurer
+	logger        log.Logger
+	ensurer       PrometheusRulesEnsurer
+	reconcileOpts ReconcileOptions
+	sharding      ShardingStrategy
 }
 
 type PrometheusRulesEnsurer interface {
 	EnsurePrometheusRule(ctx context.Context, pr *monitoringv1.PrometheusRule) error
+	EnsureAlertmanagerConfig(ctx context.Context, amc *monitoringv1alpha1.AlertmanagerConfig) error
+
+	// GetPrometheusRule returns the previously stored rule, or nil if it doesn't exist yet. Used
+	// by the ownership-aware reconciliation mode to detect rule groups Sloth no longer produces.
+	GetPrometheusRule(ctx context.Context, namespace, name string) (*monitoringv1.PrometheusRule, error)
+	// ApplyPrometheusRule server-side applies pr using fieldManager, so hand-edited fields owned
+	// by other managers survive.
+	ApplyPrometheusRule(ctx context.Context, pr *monitoringv1.PrometheusRule, fieldManager string) error
+	// ApplyAlertmanagerConfig server-side applies amc using fieldManager.
+	ApplyAlertmanagerConfig(ctx context.Context, amc *monitoringv1alpha1.AlertmanagerConfig, fieldManager string) error
+
+	// ListPrometheusRuleShards returns the names of the `PrometheusRule` CRs previously produced
+	// for `baseName` (matched through the `sloth.slok.dev/shard-of` label), so `StoreSLOs` can
+	// garbage-collect shards that became empty.
+	ListPrometheusRuleShards(ctx context.Context, namespace, baseName string) ([]string, error)
+	// DeletePrometheusRule deletes a shard CR that's no longer produced.
+	DeletePrometheusRule(ctx context.Context, namespace, name string) error
 }
 
 //go:generate mockery --case underscore --output k8sprometheusmock --outpkg k8sprometheusmock --name PrometheusRulesEnsurer
 
-func (p PrometheusOperatorCRDRepo) StoreSLOs(ctx context.Context, kmeta K8sMeta, slos []StorageSLO) error {
-	// Map to the Prometheus operator CRD.
-	rule, err := mapModelToPrometheusOperator(ctx, kmeta, slos)
+func (p PrometheusOperatorCRDRepo) StoreSLOs(ctx context.Context, kmeta K8sMeta, slos []StorageSLO) ([]string, error) {
+	shards := p.sharding.shardSLOs(kmeta.Name, slos)
+
+	crNames := make([]string, 0, len(shards))
+	for _, shard := range shards {
+		shardKmeta := kmeta
+		shardKmeta.Name = shard.name
+
+		err := p.storeShard(ctx, kmeta.Name, shardKmeta, shard.slos)
+		if err != nil {
+			return nil, err
+		}
+		crNames = append(crNames, shard.name)
+	}
+
+	err := p.pruneEmptyShards(ctx, kmeta, crNames)
+	if err != nil {
+		return nil, err
+	}
+
+	globalRule, err := mapDefaultRegistryToPrometheusRule(kmeta)
+	if err != nil {
+		return nil, err
+	}
+	if globalRule != nil {
+		err = p.storeGlobalRule(ctx, globalRule)
+		if err != nil {
+			return nil, err
+		}
+		crNames = append(crNames, globalRule.Name)
+	}
+
+	return crNames, nil
+}
+
+// storeGlobalRule stores the dedicated `globalRulesCRName` CR built by
+// `mapDefaultRegistryToPrometheusRule`. Unlike `storeShard`, it deliberately never sets an
+// `OwnerReference`: `StoreSLOs` runs once per `PrometheusServiceLevel`, and every PSL in the
+// namespace shares this one CR, so owning it by whichever PSL happened to write it last would
+// cascade-delete the global rules every other PSL still depends on the moment that one PSL is
+// removed.
+func (p PrometheusOperatorCRDRepo) storeGlobalRule(ctx context.Context, rule *monitoringv1.PrometheusRule) error {
+	if p.reconcileOpts.Enabled {
+		return p.applyRule(ctx, rule)
+	}
+
+	err := p.ensurer.EnsurePrometheusRule(ctx, rule)
+	if err != nil {
+		return fmt.Errorf("could not ensure global Prometheus operator rule CR: %w", err)
+	}
+
+	return nil
+}
+
+func (p PrometheusOperatorCRDRepo) storeShard(ctx context.Context, baseName string, kmeta K8sMeta, slos []StorageSLO) error {
+	// Map to the Prometheus operator CRDs.
+	rule, amConfigs, err := mapModelToPrometheusOperator(ctx, kmeta, slos)
 	if err != nil {
 		return fmt.Errorf("could not map model to Prometheus operator CR: %w", err)
 	}
 
-	// Add object reference.
-	rule.ObjectMeta.OwnerReferences = append(rule.ObjectMeta.OwnerReferences, metav1.OwnerReference{
+	if rule.Labels == nil {
+		rule.Labels = map[string]string{}
+	}
+	rule.Labels[shardOfLabel] = baseName
+
+	for _, slo := range slos {
+		p.logger.WithValues(log.Kv{
+			"slo_id":       slo.SLO.ID,
+			"cr_namespace": kmeta.Namespace,
+			"cr_name":      kmeta.Name,
+			"group_count":  len(rule.Spec.Groups),
+			"rule_count":   countRules(rule),
+		}).Debugf("storing SLO Prometheus rules")
+	}
+
+	// kmeta.Name is this shard's CR name; the parent resource is still named baseName.
+	ownerKmeta := kmeta
+	ownerKmeta.Name = baseName
+
+	if p.reconcileOpts.Enabled {
+		return p.reconcile(ctx, ownerKmeta, rule, amConfigs)
+	}
+
+	ownerRef := metav1.OwnerReference{
 		Kind:       kmeta.Kind,
 		APIVersion: kmeta.APIVersion,
-		Name:       kmeta.Name,
+		Name:       ownerKmeta.Name,
 		UID:        types.UID(kmeta.UID),
-	})
+	}
+
+	// Add object reference.
+	rule.ObjectMeta.OwnerReferences = append(rule.ObjectMeta.OwnerReferences, ownerRef)
 
 	// Create on API server.
 	err = p.ensurer.EnsurePrometheusRule(ctx, rule)
@@ -198,5 +537,42 @@ func (p PrometheusOperatorCRDRepo) StoreSLOs(ctx context.Context, kmeta K8sMeta,
 		return fmt.Errorf("could not ensure Prometheus operator rule CR: %w", err)
 	}
 
+	for _, amConfig := range amConfigs {
+		amConfig.ObjectMeta.OwnerReferences = append(amConfig.ObjectMeta.OwnerReferences, ownerRef)
+
+		err = p.ensurer.EnsureAlertmanagerConfig(ctx, amConfig)
+		if err != nil {
+			return fmt.Errorf("could not ensure Alertmanager config CR: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// pruneEmptyShards deletes `PrometheusRule` shard CRs Sloth produced for `kmeta.Name` on a
+// previous run but that aren't part of the current shard set (e.g. because SLOs were removed
+// and a shard emptied out).
+func (p PrometheusOperatorCRDRepo) pruneEmptyShards(ctx context.Context, kmeta K8sMeta, currentShards []string) error {
+	existing, err := p.ensurer.ListPrometheusRuleShards(ctx, kmeta.Namespace, kmeta.Name)
+	if err != nil {
+		return fmt.Errorf("could not list previous Prometheus rule shards: %w", err)
+	}
+
+	current := make(map[string]struct{}, len(currentShards))
+	for _, name := range currentShards {
+		current[name] = struct{}{}
+	}
+
+	for _, name := range existing {
+		if _, ok := current[name]; ok {
+			continue
+		}
+
+		err = p.ensurer.DeletePrometheusRule(ctx, kmeta.Namespace, name)
+		if err != nil {
+			return fmt.Errorf("could not delete empty Prometheus rule shard %q: %w", name, err)
+		}
+	}
+
 	return nil
 }